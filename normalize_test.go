@@ -0,0 +1,99 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeFilterValue(t *testing.T) {
+	hex := "507f1f77bcf86cd799439011"
+	oid, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		t.Fatalf("test fixture hex %q does not parse: %v", hex, err)
+	}
+
+	cases := []struct {
+		name string
+		in   bson.M
+		want bson.M
+	}{
+		{
+			name: "bare _id hex string",
+			in:   bson.M{"_id": hex},
+			want: bson.M{"_id": oid},
+		},
+		{
+			name: "_id $oid document",
+			in:   bson.M{"_id": bson.M{"$oid": hex}},
+			want: bson.M{"_id": oid},
+		},
+		{
+			name: "_id $eq hex string",
+			in:   bson.M{"_id": bson.M{"$eq": hex}},
+			want: bson.M{"_id": bson.M{"$eq": oid}},
+		},
+		{
+			name: "_id $ne hex string",
+			in:   bson.M{"_id": bson.M{"$ne": hex}},
+			want: bson.M{"_id": bson.M{"$ne": oid}},
+		},
+		{
+			name: "_id $in hex strings",
+			in:   bson.M{"_id": bson.M{"$in": bson.A{hex, hex}}},
+			want: bson.M{"_id": bson.M{"$in": bson.A{oid, oid}}},
+		},
+		{
+			name: "_id $nin hex strings",
+			in:   bson.M{"_id": bson.M{"$nin": bson.A{hex}}},
+			want: bson.M{"_id": bson.M{"$nin": bson.A{oid}}},
+		},
+		{
+			name: "_id $all hex strings",
+			in:   bson.M{"_id": bson.M{"$all": bson.A{hex}}},
+			want: bson.M{"_id": bson.M{"$all": bson.A{oid}}},
+		},
+		{
+			name: "_id nested under $and",
+			in:   bson.M{"$and": bson.A{bson.M{"_id": hex}, bson.M{"status": "active"}}},
+			want: bson.M{"$and": bson.A{bson.M{"_id": oid}, bson.M{"status": "active"}}},
+		},
+		{
+			name: "non-id field with $oid-shaped value is left alone",
+			in:   bson.M{"ref": bson.M{"$oid": hex}},
+			want: bson.M{"ref": bson.M{"$oid": hex}},
+		},
+		{
+			name: "non-hex _id string is left alone",
+			in:   bson.M{"_id": "not-an-oid"},
+			want: bson.M{"_id": "not-an-oid"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := normalizeFilter(tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("normalizeFilter(%v) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeFilterValueBareOidDoesNotPanic guards against a bare
+// {"$oid": "..."} filter/pipeline-stage (not assigned to a field) being
+// collapsed into a primitive.ObjectID, which previously made
+// normalizeFilter's bson.M type assertion panic.
+func TestNormalizeFilterValueBareOidDoesNotPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("normalizeFilter panicked on a bare $oid filter: %v", r)
+		}
+	}()
+	got := normalizeFilter(bson.M{"$oid": "507f1f77bcf86cd799439011"})
+	if _, ok := got["$oid"]; !ok {
+		t.Errorf("normalizeFilter(bare $oid) = %v, want the $oid key preserved", got)
+	}
+}
@@ -1,559 +1,1659 @@
-package main
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"strings"
-	"sync"
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-)
-
-// Struct สำหรับรับ Request (ตาม Log)
-type RequestPayload struct {
-	Cur      string `json:"cur"`
-	Currency string `json:"currency"`
-	Month    string `json:"month"`
-	Year     string `json:"year"`
-	Username string `json:"username"`
-	Web      string `json:"web"`
-}
-
-// Struct สำหรับส่ง Response กลับ (ตาม Log)
-type ResponseData struct {
-	Username    string  `json:"username"`
-	Prefix      *string `json:"prefix"` // ใช้ *string เพราะใน log เป็น null/nil
-	Currency    string  `json:"currency"`
-	BetAmt      float64 `json:"betAmt"`
-	ValidAmount float64 `json:"validAmount"`
-	MemberWl    float64 `json:"memberWl"`
-	MemberComm  float64 `json:"memberComm"`
-	MemberTotal float64 `json:"memberTotal"`
-}
-
-type ResponseBody struct {
-	Code int          `json:"code"`
-	Msg  string       `json:"msg"`
-	Data ResponseData `json:"data"`
-}
-
-type SnapshotItem struct {
-	MemberComm  float64 `bson:"memberComm"`
-	MemberTotal float64 `bson:"memberTotal"`
-	MemberWl    float64 `bson:"memberWl"`
-	Prefix      *string `bson:"prefix"`
-	Username    string  `bson:"username"`
-	ValidAmount float64 `bson:"validAmount"`
-	BetAmt      float64 `bson:"betAmt"`
-	Currency    string  `bson:"currency"`
-	Web         string  `bson:"web"`
-	Month       string  `bson:"month"`
-	Year        string  `bson:"year"`
-}
-
-type Snapshot struct {
-	ClientName string         `bson:"client_name"`
-	Prefix     string         `bson:"prefix"`
-	Data       []SnapshotItem `bson:"data"`
-}
-
-var (
-	mongoOnce   sync.Once
-	mongoClient *mongo.Client
-	mongoErr    error
-)
-
-type localConfig struct {
-	MongoURI string `json:"mongo_uri"`
-}
-
-func loadDotEnv(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		val := strings.TrimSpace(parts[1])
-		if key == "" {
-			continue
-		}
-		if _, exists := os.LookupEnv(key); !exists {
-			_ = os.Setenv(key, val)
-		}
-	}
-
-	return nil
-}
-
-func loadMongoURI() (string, error) {
-	_ = loadDotEnv(".env")
-
-	if uri := os.Getenv("MONGO_URI"); uri != "" {
-		return uri, nil
-	}
-
-	data, err := os.ReadFile("config.json")
-	if err == nil {
-		var cfg localConfig
-		if err := json.Unmarshal(data, &cfg); err != nil {
-			return "", fmt.Errorf("invalid config.json: %w", err)
-		}
-		if cfg.MongoURI != "" {
-			return cfg.MongoURI, nil
-		}
-	}
-
-	return "", fmt.Errorf("missing MONGO_URI (env or config.json)")
-}
-
-func getMongoClient() (*mongo.Client, error) {
-	mongoOnce.Do(func() {
-		uri, err := loadMongoURI()
-		if err != nil {
-			log.Printf("mongo: %v", err)
-			mongoErr = err
-			return
-		}
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-		if err != nil {
-			log.Printf("mongo: connect failed: %v", err)
-			mongoErr = err
-			return
-		}
-
-		if err := client.Ping(ctx, nil); err != nil {
-			log.Printf("mongo: ping failed: %v", err)
-			mongoErr = err
-			return
-		}
-
-		log.Printf("mongo: connected")
-		mongoClient = client
-	})
-
-	return mongoClient, mongoErr
-}
-
-func winloseHandler(w http.ResponseWriter, r *http.Request) {
-	// 1. ตรวจสอบว่าเป็น POST Method หรือไม่
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// 2. อ่าน Body ที่ส่งมา (เพื่อดูว่าหน้าตาเหมือนที่คาดหวังไหม)
-	var req RequestPayload
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
-	}
-
-	fmt.Printf("Received Request: %+v\n", req)
-
-	client, err := getMongoClient()
-	if err != nil {
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-
-	var conds []bson.M
-	if req.Month != "" {
-		// รองรับทั้ง "01" และ "1" สำหรับเดือน
-		monthPatterns := []string{req.Month}
-		if len(req.Month) == 1 && req.Month >= "1" && req.Month <= "9" {
-			// ถ้าเป็น "1"-"9" เพิ่ม "01"-"09"
-			monthPatterns = append(monthPatterns, "0"+req.Month)
-		} else if len(req.Month) == 2 && req.Month[0] == '0' && req.Month[1] >= '1' && req.Month[1] <= '9' {
-			// ถ้าเป็น "01"-"09" เพิ่ม "1"-"9"
-			monthPatterns = append(monthPatterns, string(req.Month[1]))
-		}
-		
-		var monthConds []bson.M
-		for _, m := range monthPatterns {
-			monthConds = append(monthConds, bson.M{"month": m})
-			monthConds = append(monthConds, bson.M{"data.month": m})
-		}
-		conds = append(conds, bson.M{"$or": monthConds})
-	}
-	if req.Year != "" {
-		conds = append(conds, bson.M{
-			"$or": []bson.M{
-				{"year": req.Year},
-				{"data.year": req.Year},
-			},
-		})
-	}
-	if req.Username != "" {
-		conds = append(conds, bson.M{"data.username": req.Username})
-	}
-	// รองรับทั้ง 'cur' และ 'currency' parameter
-	currencyValue := req.Cur
-	if currencyValue == "" {
-		currencyValue = req.Currency
-	}
-	if currencyValue != "" {
-		conds = append(conds, bson.M{"data.currency": currencyValue})
-	}
-	if req.Web != "" {
-		conds = append(conds, bson.M{
-			"$or": []bson.M{
-				{"client_name": req.Web},
-				{"data.web": req.Web},
-			},
-		})
-	}
-
-	filter := bson.M{}
-	if len(conds) > 0 {
-		filter["$and"] = conds
-	}
-
-	collection := client.Database("test_data").Collection("snapshot")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	var raw bson.M
-	if err := collection.FindOne(ctx, filter).Decode(&raw); err != nil {
-		http.Error(w, "Record not found", http.StatusNotFound)
-		return
-	}
-
-	rawData, ok := raw["data"]
-	if !ok || rawData == nil {
-		http.Error(w, "Record not found", http.StatusNotFound)
-		return
-	}
-
-	var items []SnapshotItem
-	switch v := rawData.(type) {
-	case bson.M:
-		var item SnapshotItem
-		if dataBytes, err := bson.Marshal(v); err == nil {
-			_ = bson.Unmarshal(dataBytes, &item)
-			items = append(items, item)
-		}
-	case map[string]interface{}:
-		var item SnapshotItem
-		if dataBytes, err := bson.Marshal(v); err == nil {
-			_ = bson.Unmarshal(dataBytes, &item)
-			items = append(items, item)
-		}
-	case []interface{}:
-		for _, entry := range v {
-			asMap, ok := entry.(map[string]interface{})
-			if !ok {
-				if asBson, ok := entry.(bson.M); ok {
-					asMap = asBson
-				} else {
-					continue
-				}
-			}
-			var item SnapshotItem
-			if dataBytes, err := bson.Marshal(asMap); err == nil {
-				_ = bson.Unmarshal(dataBytes, &item)
-				items = append(items, item)
-			}
-		}
-	}
-
-	if len(items) == 0 {
-		http.Error(w, "Record not found", http.StatusNotFound)
-		return
-	}
-
-	var item *SnapshotItem
-	for i := range items {
-		candidate := &items[i]
-		if req.Username != "" && candidate.Username != req.Username {
-			continue
-		}
-		if req.Cur != "" && candidate.Currency != req.Cur {
-			continue
-		}
-		if req.Web != "" && candidate.Web != "" && candidate.Web != req.Web {
-			continue
-		}
-		item = candidate
-		break
-	}
-	if item == nil {
-		item = &items[0]
-	}
-
-	// 3. เตรียมข้อมูล Response (Mock Data จาก Log ของคุณ)
-	mockResponse := ResponseBody{
-		Code: 0,
-		Msg:  "SUCCESS",
-		Data: ResponseData{
-			Username:    item.Username,
-			Prefix:      item.Prefix,
-			Currency:    item.Currency,
-			BetAmt:      item.BetAmt,
-			ValidAmount: item.ValidAmount,
-			MemberWl:    item.MemberWl,
-			MemberComm:  item.MemberComm,
-			MemberTotal: item.MemberTotal,
-		},
-	}
-
-	// 4. ตั้งค่า Header และส่ง JSON กลับไป
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(mockResponse)
-}
-
-func snapshotAllHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	client, err := getMongoClient()
-	if err != nil {
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-
-	collection := client.Database("test_data").Collection("snapshot")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	cursor, err := collection.Find(ctx, bson.M{})
-	if err != nil {
-		http.Error(w, "Query failed", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	var snapshots []bson.M
-	if err := cursor.All(ctx, &snapshots); err != nil {
-		http.Error(w, "Decode failed", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(snapshots)
-}
-
-func insertSnapshotHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var doc bson.M
-	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
-	}
-	if len(doc) == 0 {
-		http.Error(w, "Empty body", http.StatusBadRequest)
-		return
-	}
-
-	client, err := getMongoClient()
-	if err != nil {
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-
-	collection := client.Database("test_data").Collection("snapshot")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	res, err := collection.InsertOne(ctx, doc)
-	if err != nil {
-		http.Error(w, "Insert failed", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(bson.M{
-		"code":       0,
-		"msg":        "SUCCESS",
-		"insertedId": res.InsertedID,
-	})
-}
-
-type modifyRequest struct {
-	Filter bson.M `json:"filter"`
-	Update bson.M `json:"update"`
-	Upsert bool   `json:"upsert"`
-}
-
-type deleteRequest struct {
-	Filter bson.M `json:"filter"`
-}
-
-func normalizeFilter(filter bson.M) bson.M {
-	if filter == nil {
-		return bson.M{}
-	}
-	if idVal, ok := filter["_id"]; ok {
-		switch v := idVal.(type) {
-		case string:
-			if oid, err := primitive.ObjectIDFromHex(v); err == nil {
-				filter["_id"] = oid
-			}
-		case map[string]interface{}:
-			if hex, ok := v["$oid"].(string); ok {
-				if oid, err := primitive.ObjectIDFromHex(hex); err == nil {
-					filter["_id"] = oid
-				}
-			}
-		}
-	}
-	return filter
-}
-
-func updateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req modifyRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
-	}
-	if len(req.Filter) == 0 || len(req.Update) == 0 {
-		http.Error(w, "Missing filter or update", http.StatusBadRequest)
-		return
-	}
-
-	client, err := getMongoClient()
-	if err != nil {
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-
-	filter := normalizeFilter(req.Filter)
-	update := bson.M{"$set": req.Update}
-
-	collection := client.Database("test_data").Collection("snapshot")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	res, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(req.Upsert))
-	if err != nil {
-		http.Error(w, "Update failed", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(bson.M{
-		"code":      0,
-		"msg":       "SUCCESS",
-		"matched":   res.MatchedCount,
-		"modified":  res.ModifiedCount,
-		"upserted":  res.UpsertedID,
-	})
-}
-
-func deleteSnapshotHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req deleteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
-	}
-	if len(req.Filter) == 0 {
-		http.Error(w, "Missing filter", http.StatusBadRequest)
-		return
-	}
-
-	client, err := getMongoClient()
-	if err != nil {
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-
-	filter := normalizeFilter(req.Filter)
-
-	collection := client.Database("test_data").Collection("snapshot")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	res, err := collection.DeleteOne(ctx, filter)
-	if err != nil {
-		http.Error(w, "Delete failed", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(bson.M{
-		"code":    0,
-		"msg":     "SUCCESS",
-		"deleted": res.DeletedCount,
-	})
-}
-
-func withCORS(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next(w, r)
-	}
-}
-
-func main() {
-	// สร้าง Route ให้ตรงกับ Path ใน Log
-	// URL เดิม: https://api-topup.sportbookprivate.com
-	http.HandleFunc("/api/v1/ext/winloseEsByMonthMulti", withCORS(winloseHandler))
-	http.HandleFunc("/api/v1/ext/snapshotAll", withCORS(snapshotAllHandler))
-	http.HandleFunc("/api/v1/ext/insertSnapshot", withCORS(insertSnapshotHandler))
-	http.HandleFunc("/api/v1/ext/updateSnapshot", withCORS(updateSnapshotHandler))
-	http.HandleFunc("/api/v1/ext/deleteSnapshot", withCORS(deleteSnapshotHandler))
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	fmt.Printf("Mock Server started at port %s\n", port)
-	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/winloseEsByMonthMulti\n", port)
-	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/snapshotAll\n", port)
-	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/insertSnapshot\n", port)
-	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/updateSnapshot\n", port)
-	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/deleteSnapshot\n", port)
-
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal(err)
-	}
-}
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Struct สำหรับรับ Request (ตาม Log)
+type RequestPayload struct {
+	Cur      string `json:"cur"`
+	Currency string `json:"currency"`
+	Month    string `json:"month"`
+	Year     string `json:"year"`
+	Username string `json:"username"`
+	Web      string `json:"web"`
+}
+
+// Struct สำหรับส่ง Response กลับ (ตาม Log)
+type ResponseData struct {
+	Username    string  `json:"username"`
+	Prefix      *string `json:"prefix"` // ใช้ *string เพราะใน log เป็น null/nil
+	Currency    string  `json:"currency"`
+	BetAmt      float64 `json:"betAmt"`
+	ValidAmount float64 `json:"validAmount"`
+	MemberWl    float64 `json:"memberWl"`
+	MemberComm  float64 `json:"memberComm"`
+	MemberTotal float64 `json:"memberTotal"`
+}
+
+type ResponseBody struct {
+	Code int          `json:"code"`
+	Msg  string       `json:"msg"`
+	Data ResponseData `json:"data"`
+}
+
+type SnapshotItem struct {
+	MemberComm  float64 `bson:"memberComm"`
+	MemberTotal float64 `bson:"memberTotal"`
+	MemberWl    float64 `bson:"memberWl"`
+	Prefix      *string `bson:"prefix"`
+	Username    string  `bson:"username"`
+	ValidAmount float64 `bson:"validAmount"`
+	BetAmt      float64 `bson:"betAmt"`
+	Currency    string  `bson:"currency"`
+	Web         string  `bson:"web"`
+	Month       string  `bson:"month"`
+	Year        string  `bson:"year"`
+}
+
+type Snapshot struct {
+	ClientName string         `bson:"client_name"`
+	Prefix     string         `bson:"prefix"`
+	Data       []SnapshotItem `bson:"data"`
+}
+
+var (
+	mongoOnce   sync.Once
+	mongoClient *mongo.Client
+	mongoErr    error
+)
+
+type localConfig struct {
+	MongoURI  string           `json:"mongo_uri"`
+	Resources []resourceConfig `json:"resources"`
+}
+
+// resourceConfig describes one collection exposed under the generic REST
+// facade: where it's mounted, which database/collection backs it, the
+// dotted fields that make up its natural key (for upsertSnapshot and the
+// unique index), and whether the write routes (insert/update/delete/bulk/
+// upsert) should be registered at all. Name feeds registerResourceRoutes'
+// naming convention (e.g. Path "/api/v1/ext" + Name "snapshot" reproduces
+// the module's original fixed routes like "/api/v1/ext/snapshotAll").
+type resourceConfig struct {
+	Path       string   `json:"path"`
+	Name       string   `json:"name"`
+	Database   string   `json:"database"`
+	Collection string   `json:"collection"`
+	NaturalKey []string `json:"naturalKey"`
+	ReadOnly   bool     `json:"readOnly"`
+}
+
+// defaultResources preserves the module's original single-collection
+// behavior, and its original fixed route paths, when config.json doesn't
+// list any resources.
+func defaultResources() []resourceConfig {
+	return []resourceConfig{
+		{
+			Path:       "/api/v1/ext",
+			Name:       "snapshot",
+			Database:   "test_data",
+			Collection: "snapshot",
+			NaturalKey: snapshotNaturalKeyFields,
+			ReadOnly:   false,
+		},
+	}
+}
+
+// loadResources reads the "resources" list from config.json, falling back
+// to defaultResources so the module still serves a snapshot collection out
+// of the box.
+func loadResources() []resourceConfig {
+	data, err := os.ReadFile("config.json")
+	if err == nil {
+		var cfg localConfig
+		if err := json.Unmarshal(data, &cfg); err == nil && len(cfg.Resources) > 0 {
+			return cfg.Resources
+		}
+	}
+	return defaultResources()
+}
+
+func loadDotEnv(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+		if key == "" {
+			continue
+		}
+		if _, exists := os.LookupEnv(key); !exists {
+			_ = os.Setenv(key, val)
+		}
+	}
+
+	return nil
+}
+
+func loadMongoURI() (string, error) {
+	_ = loadDotEnv(".env")
+
+	if uri := os.Getenv("MONGO_URI"); uri != "" {
+		return uri, nil
+	}
+
+	data, err := os.ReadFile("config.json")
+	if err == nil {
+		var cfg localConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return "", fmt.Errorf("invalid config.json: %w", err)
+		}
+		if cfg.MongoURI != "" {
+			return cfg.MongoURI, nil
+		}
+	}
+
+	return "", fmt.Errorf("missing MONGO_URI (env or config.json)")
+}
+
+func getMongoClient() (*mongo.Client, error) {
+	mongoOnce.Do(func() {
+		uri, err := loadMongoURI()
+		if err != nil {
+			log.Printf("mongo: %v", err)
+			mongoErr = err
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			log.Printf("mongo: connect failed: %v", err)
+			mongoErr = err
+			return
+		}
+
+		if err := client.Ping(ctx, nil); err != nil {
+			log.Printf("mongo: ping failed: %v", err)
+			mongoErr = err
+			return
+		}
+
+		log.Printf("mongo: connected")
+		mongoClient = client
+	})
+
+	return mongoClient, mongoErr
+}
+
+// ErrorDetail describes one field-level validation failure so clients can
+// act on "errors" programmatically instead of parsing the "msg" string.
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// writeError replaces the old http.Error(w, "...", status) calls with a
+// JSON envelope, so failures are as machine-readable as successes. The
+// status code doubles as the body's "code" field, keeping it nonzero like
+// the ad-hoc HTTP statuses it replaces.
+func writeError(w http.ResponseWriter, status int, msg string, details ...ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":   status,
+		"msg":    msg,
+		"errors": details,
+	})
+}
+
+var (
+	monthPattern = regexp.MustCompile(`^(0?[1-9]|1[0-2])$`)
+	yearPattern  = regexp.MustCompile(`^[0-9]{4}$`)
+)
+
+// validateRequestPayload enforces the shapes winloseHandler has always
+// assumed but never checked: a two-digit-or-bare month, a four-digit year,
+// and a year whenever a month is supplied (a month alone is ambiguous).
+func validateRequestPayload(req RequestPayload) []ErrorDetail {
+	var errs []ErrorDetail
+	if req.Month != "" {
+		if !monthPattern.MatchString(req.Month) {
+			errs = append(errs, ErrorDetail{Field: "month", Rule: "format", Message: "month must be 01-12 or 1-12"})
+		}
+		if req.Year == "" {
+			errs = append(errs, ErrorDetail{Field: "year", Rule: "required_with_month", Message: "year is required when month is supplied"})
+		}
+	}
+	if req.Year != "" && !yearPattern.MatchString(req.Year) {
+		errs = append(errs, ErrorDetail{Field: "year", Rule: "format", Message: "year must be a four-digit number"})
+	}
+	return errs
+}
+
+// forbiddenFilterOperators blocks Mongo operators that execute caller
+// script (server-side JS), which a generic filter/pipeline passthrough
+// would otherwise happily forward.
+var forbiddenFilterOperators = map[string]bool{
+	"$where":       true,
+	"$function":    true,
+	"$accumulator": true,
+}
+
+func containsForbiddenOperator(v interface{}) bool {
+	switch val := v.(type) {
+	case bson.M:
+		for k, vv := range val {
+			if forbiddenFilterOperators[k] || containsForbiddenOperator(vv) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		return containsForbiddenOperator(bson.M(val))
+	case bson.A:
+		for _, vv := range val {
+			if containsForbiddenOperator(vv) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, vv := range val {
+			if containsForbiddenOperator(vv) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateFilter checks the caller-supplied filter/pipeline-stage shapes
+// shared by modifyRequest, deleteRequest, and the generic query/bulk
+// endpoints: it must be non-empty and must not smuggle in a dangerous
+// operator.
+func validateFilter(field string, filter bson.M) []ErrorDetail {
+	var errs []ErrorDetail
+	if len(filter) == 0 {
+		errs = append(errs, ErrorDetail{Field: field, Rule: "required", Message: field + " must not be empty"})
+		return errs
+	}
+	if containsForbiddenOperator(filter) {
+		errs = append(errs, ErrorDetail{Field: field, Rule: "forbidden_operator", Message: "filter may not use $where, $function, or $accumulator"})
+	}
+	return errs
+}
+
+// resourceHandlers binds the generic CRUD+query+bulk+stream handlers to one
+// configured resource, so the same handler code serves any database/
+// collection pair instead of being copy-pasted per collection. Each
+// resource gets its own streamHub since sharedStream keys are only unique
+// within a single collection's change stream.
+type resourceHandlers struct {
+	res resourceConfig
+	hub *streamHub
+}
+
+func newResourceHandlers(res resourceConfig) *resourceHandlers {
+	return &resourceHandlers{res: res, hub: &streamHub{streams: make(map[string]*sharedStream)}}
+}
+
+func (h *resourceHandlers) winloseHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. ตรวจสอบว่าเป็น POST Method หรือไม่
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 2. อ่าน Body ที่ส่งมา (เพื่อดูว่าหน้าตาเหมือนที่คาดหวังไหม)
+	var req RequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if errs := validateRequestPayload(req); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+		return
+	}
+
+	fmt.Printf("Received Request: %+v\n", req)
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	var conds []bson.M
+	if req.Month != "" {
+		// รองรับทั้ง "01" และ "1" สำหรับเดือน
+		monthPatterns := []string{req.Month}
+		if len(req.Month) == 1 && req.Month >= "1" && req.Month <= "9" {
+			// ถ้าเป็น "1"-"9" เพิ่ม "01"-"09"
+			monthPatterns = append(monthPatterns, "0"+req.Month)
+		} else if len(req.Month) == 2 && req.Month[0] == '0' && req.Month[1] >= '1' && req.Month[1] <= '9' {
+			// ถ้าเป็น "01"-"09" เพิ่ม "1"-"9"
+			monthPatterns = append(monthPatterns, string(req.Month[1]))
+		}
+
+		var monthConds []bson.M
+		for _, m := range monthPatterns {
+			monthConds = append(monthConds, bson.M{"month": m})
+			monthConds = append(monthConds, bson.M{"data.month": m})
+		}
+		conds = append(conds, bson.M{"$or": monthConds})
+	}
+	if req.Year != "" {
+		conds = append(conds, bson.M{
+			"$or": []bson.M{
+				{"year": req.Year},
+				{"data.year": req.Year},
+			},
+		})
+	}
+	if req.Username != "" {
+		conds = append(conds, bson.M{"data.username": req.Username})
+	}
+	// รองรับทั้ง 'cur' และ 'currency' parameter
+	currencyValue := req.Cur
+	if currencyValue == "" {
+		currencyValue = req.Currency
+	}
+	if currencyValue != "" {
+		conds = append(conds, bson.M{"data.currency": currencyValue})
+	}
+	if req.Web != "" {
+		conds = append(conds, bson.M{
+			"$or": []bson.M{
+				{"client_name": req.Web},
+				{"data.web": req.Web},
+			},
+		})
+	}
+
+	filter := bson.M{}
+	if len(conds) > 0 {
+		filter["$and"] = conds
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 10*time.Second)
+	defer cancel()
+
+	var raw bson.M
+	if err := collection.FindOne(ctx, filter).Decode(&raw); err != nil {
+		writeError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+
+	rawData, ok := raw["data"]
+	if !ok || rawData == nil {
+		writeError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+
+	var items []SnapshotItem
+	switch v := rawData.(type) {
+	case bson.M:
+		var item SnapshotItem
+		if dataBytes, err := bson.Marshal(v); err == nil {
+			_ = bson.Unmarshal(dataBytes, &item)
+			items = append(items, item)
+		}
+	case map[string]interface{}:
+		var item SnapshotItem
+		if dataBytes, err := bson.Marshal(v); err == nil {
+			_ = bson.Unmarshal(dataBytes, &item)
+			items = append(items, item)
+		}
+	case []interface{}:
+		for _, entry := range v {
+			asMap, ok := entry.(map[string]interface{})
+			if !ok {
+				if asBson, ok := entry.(bson.M); ok {
+					asMap = asBson
+				} else {
+					continue
+				}
+			}
+			var item SnapshotItem
+			if dataBytes, err := bson.Marshal(asMap); err == nil {
+				_ = bson.Unmarshal(dataBytes, &item)
+				items = append(items, item)
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		writeError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+
+	var item *SnapshotItem
+	for i := range items {
+		candidate := &items[i]
+		if req.Username != "" && candidate.Username != req.Username {
+			continue
+		}
+		if req.Cur != "" && candidate.Currency != req.Cur {
+			continue
+		}
+		if req.Web != "" && candidate.Web != "" && candidate.Web != req.Web {
+			continue
+		}
+		item = candidate
+		break
+	}
+	if item == nil {
+		item = &items[0]
+	}
+
+	// 3. เตรียมข้อมูล Response (Mock Data จาก Log ของคุณ)
+	mockResponse := ResponseBody{
+		Code: 0,
+		Msg:  "SUCCESS",
+		Data: ResponseData{
+			Username:    item.Username,
+			Prefix:      item.Prefix,
+			Currency:    item.Currency,
+			BetAmt:      item.BetAmt,
+			ValidAmount: item.ValidAmount,
+			MemberWl:    item.MemberWl,
+			MemberComm:  item.MemberComm,
+			MemberTotal: item.MemberTotal,
+		},
+	}
+
+	// 4. ตั้งค่า Header และส่ง JSON กลับไป
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mockResponse)
+}
+
+func (h *resourceHandlers) snapshotAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 30*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Query failed")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []bson.M
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		writeError(w, http.StatusInternalServerError, "Decode failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+func (h *resourceHandlers) insertSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var doc bson.M
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(doc) == 0 {
+		writeError(w, http.StatusBadRequest, "Empty body")
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 10*time.Second)
+	defer cancel()
+
+	res, err := collection.InsertOne(ctx, doc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Insert failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":       0,
+		"msg":        "SUCCESS",
+		"insertedId": res.InsertedID,
+	})
+}
+
+type modifyRequest struct {
+	Filter bson.M `json:"filter"`
+	Update bson.M `json:"update"`
+	Upsert bool   `json:"upsert"`
+}
+
+type deleteRequest struct {
+	Filter bson.M `json:"filter"`
+}
+
+// normalizeFilter converts "_id" strings and {"$oid": "..."} documents to
+// primitive.ObjectID anywhere in the filter tree, not just at the top level,
+// so callers can nest id matches under "$and"/"$or" or dotted paths.
+func normalizeFilter(filter bson.M) bson.M {
+	if filter == nil {
+		return bson.M{}
+	}
+	return normalizeFilterValue(filter).(bson.M)
+}
+
+type queryRequest struct {
+	Filter     bson.M `json:"filter"`
+	Projection bson.M `json:"projection"`
+	Sort       bson.M `json:"sort"`
+	Limit      int64  `json:"limit"`
+	Skip       int64  `json:"skip"`
+}
+
+type aggregateRequest struct {
+	Pipeline []bson.M `json:"pipeline"`
+}
+
+// normalizeFilterValue walks filter/projection/pipeline trees so that values
+// assigned to an "_id" key are converted to primitive.ObjectID no matter how
+// deeply they are nested (e.g. inside "$and"/"$or"). It deliberately does not
+// special-case a bare {"$oid": "..."} document on its own: that shape only
+// means "convert me" when it's the value of an _id field, which
+// normalizeIDValue/normalizeIDDoc already handle — collapsing it here too
+// would turn a caller-supplied filter like {"$oid": "<hex>"} into a raw
+// primitive.ObjectID where a bson.M is required, panicking callers such as
+// normalizeFilter that type-assert the result back to bson.M.
+func normalizeFilterValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case bson.M:
+		out := make(bson.M, len(val))
+		for k, vv := range val {
+			if k == "_id" {
+				out[k] = normalizeIDValue(vv)
+				continue
+			}
+			out[k] = normalizeFilterValue(vv)
+		}
+		return out
+	case map[string]interface{}:
+		return normalizeFilterValue(bson.M(val))
+	case bson.A:
+		out := make(bson.A, len(val))
+		for i, vv := range val {
+			out[i] = normalizeFilterValue(vv)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = normalizeFilterValue(vv)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func normalizeIDValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if oid, err := primitive.ObjectIDFromHex(val); err == nil {
+			return oid
+		}
+		return val
+	case map[string]interface{}:
+		return normalizeIDDoc(bson.M(val))
+	case bson.M:
+		return normalizeIDDoc(val)
+	case bson.A:
+		out := make(bson.A, len(val))
+		for i, vv := range val {
+			out[i] = normalizeIDValue(vv)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = normalizeIDValue(vv)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// normalizeIDDoc handles a document assigned to "_id" (or nested under it):
+// a {"$oid": "..."} doc converts directly, and every operator's value
+// (whether a single candidate like "$eq"/"$ne" or an array like
+// "$in"/"$nin"/"$all") is routed back through normalizeIDValue, since any
+// value under "_id" is an id candidate and deserves the same hex/$oid
+// normalization as a bare "_id" value.
+func normalizeIDDoc(m bson.M) interface{} {
+	if oid, ok := oidFromDoc(m); ok {
+		return oid
+	}
+	out := make(bson.M, len(m))
+	for k, vv := range m {
+		out[k] = normalizeIDValue(vv)
+	}
+	return out
+}
+
+func oidFromDoc(m bson.M) (primitive.ObjectID, bool) {
+	if hex, ok := m["$oid"].(string); ok && len(m) == 1 {
+		if oid, err := primitive.ObjectIDFromHex(hex); err == nil {
+			return oid, true
+		}
+	}
+	return primitive.ObjectID{}, false
+}
+
+func (h *resourceHandlers) querySnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if containsForbiddenOperator(req.Filter) {
+		writeError(w, http.StatusBadRequest, "Validation failed",
+			ErrorDetail{Field: "filter", Rule: "forbidden_operator", Message: "filter may not use $where, $function, or $accumulator"})
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	filter := normalizeFilter(req.Filter)
+
+	findOpts := options.Find()
+	if req.Projection != nil {
+		findOpts.SetProjection(req.Projection)
+	}
+	if req.Sort != nil {
+		findOpts.SetSort(req.Sort)
+	}
+	if req.Limit > 0 {
+		findOpts.SetLimit(req.Limit)
+	}
+	if req.Skip > 0 {
+		findOpts.SetSkip(req.Skip)
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 30*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Count failed")
+		return
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Query failed")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var items []bson.M
+	if err := cursor.All(ctx, &items); err != nil {
+		writeError(w, http.StatusInternalServerError, "Decode failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":  0,
+		"msg":   "SUCCESS",
+		"total": total,
+		"items": items,
+	})
+}
+
+func (h *resourceHandlers) aggregateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req aggregateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(req.Pipeline) == 0 {
+		writeError(w, http.StatusBadRequest, "Empty pipeline")
+		return
+	}
+	for i, stage := range req.Pipeline {
+		if containsForbiddenOperator(stage) {
+			writeError(w, http.StatusBadRequest, "Validation failed",
+				ErrorDetail{Field: fmt.Sprintf("pipeline[%d]", i), Rule: "forbidden_operator", Message: "stage may not use $where, $function, or $accumulator"})
+			return
+		}
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	pipeline := make(bson.A, len(req.Pipeline))
+	for i, stage := range req.Pipeline {
+		pipeline[i] = normalizeFilterValue(stage)
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 30*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Aggregate failed")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var items []bson.M
+	if err := cursor.All(ctx, &items); err != nil {
+		writeError(w, http.StatusInternalServerError, "Decode failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":  0,
+		"msg":   "SUCCESS",
+		"total": len(items),
+		"items": items,
+	})
+}
+
+type bulkUpdateSpec struct {
+	Filter bson.M `json:"filter"`
+	Update bson.M `json:"update"`
+	Upsert bool   `json:"upsert"`
+	Many   bool   `json:"many"`
+}
+
+type bulkFilterSpec struct {
+	Filter bson.M `json:"filter"`
+	Many   bool   `json:"many"`
+}
+
+type bulkReplaceSpec struct {
+	Filter      bson.M `json:"filter"`
+	Replacement bson.M `json:"replacement"`
+	Upsert      bool   `json:"upsert"`
+}
+
+type bulkOp struct {
+	Insert  bson.M           `json:"insert"`
+	Update  *bulkUpdateSpec  `json:"update"`
+	Delete  *bulkFilterSpec  `json:"delete"`
+	Replace *bulkReplaceSpec `json:"replace"`
+}
+
+type bulkRequest struct {
+	Ops     []bulkOp `json:"ops"`
+	Ordered *bool    `json:"ordered"`
+}
+
+type bulkOpSummary struct {
+	Insert  int `json:"insert"`
+	Update  int `json:"update"`
+	Delete  int `json:"delete"`
+	Replace int `json:"replace"`
+}
+
+func (h *resourceHandlers) bulkSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(req.Ops) == 0 {
+		writeError(w, http.StatusBadRequest, "Empty ops")
+		return
+	}
+
+	var summary bulkOpSummary
+	models := make([]mongo.WriteModel, 0, len(req.Ops))
+	for i, op := range req.Ops {
+		switch {
+		case op.Insert != nil:
+			if len(op.Insert) == 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed",
+					ErrorDetail{Field: fmt.Sprintf("ops[%d]", i), Rule: "required", Message: "insert op requires a non-empty document"})
+				return
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(op.Insert))
+			summary.Insert++
+		case op.Update != nil:
+			if len(op.Update.Update) == 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed",
+					ErrorDetail{Field: fmt.Sprintf("ops[%d]", i), Rule: "required", Message: "update op requires filter and update"})
+				return
+			}
+			if errs := validateFilter(fmt.Sprintf("ops[%d].filter", i), op.Update.Filter); len(errs) > 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+				return
+			}
+			filter := normalizeFilter(op.Update.Filter)
+			update := bson.M{"$set": op.Update.Update}
+			if op.Update.Many {
+				models = append(models, mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(update).SetUpsert(op.Update.Upsert))
+			} else {
+				models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(op.Update.Upsert))
+			}
+			summary.Update++
+		case op.Delete != nil:
+			if errs := validateFilter(fmt.Sprintf("ops[%d].filter", i), op.Delete.Filter); len(errs) > 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+				return
+			}
+			filter := normalizeFilter(op.Delete.Filter)
+			if op.Delete.Many {
+				models = append(models, mongo.NewDeleteManyModel().SetFilter(filter))
+			} else {
+				models = append(models, mongo.NewDeleteOneModel().SetFilter(filter))
+			}
+			summary.Delete++
+		case op.Replace != nil:
+			if len(op.Replace.Replacement) == 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed",
+					ErrorDetail{Field: fmt.Sprintf("ops[%d]", i), Rule: "required", Message: "replace op requires filter and replacement"})
+				return
+			}
+			if errs := validateFilter(fmt.Sprintf("ops[%d].filter", i), op.Replace.Filter); len(errs) > 0 {
+				writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+				return
+			}
+			filter := normalizeFilter(op.Replace.Filter)
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(op.Replace.Replacement).SetUpsert(op.Replace.Upsert))
+			summary.Replace++
+		default:
+			writeError(w, http.StatusBadRequest, "Validation failed",
+				ErrorDetail{Field: fmt.Sprintf("ops[%d]", i), Rule: "unrecognized_operation", Message: "op must set exactly one of insert, update, delete, replace"})
+			return
+		}
+	}
+
+	ordered := true
+	if req.Ordered != nil {
+		ordered = *req.Ordered
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 30*time.Second)
+	defer cancel()
+
+	res, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Bulk write failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":     0,
+		"msg":      "SUCCESS",
+		"ops":      summary,
+		"matched":  res.MatchedCount,
+		"modified": res.ModifiedCount,
+		"inserted": res.InsertedCount,
+		"deleted":  res.DeletedCount,
+		"upserted": res.UpsertedCount,
+	})
+}
+
+func (h *resourceHandlers) updateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req modifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	errs := validateFilter("filter", req.Filter)
+	if len(req.Update) == 0 {
+		errs = append(errs, ErrorDetail{Field: "update", Rule: "required", Message: "update must not be empty"})
+	}
+	if len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	filter := normalizeFilter(req.Filter)
+	update := bson.M{"$set": req.Update}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 10*time.Second)
+	defer cancel()
+
+	res, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(req.Upsert))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Update failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":     0,
+		"msg":      "SUCCESS",
+		"matched":  res.MatchedCount,
+		"modified": res.ModifiedCount,
+		"upserted": res.UpsertedID,
+	})
+}
+
+func (h *resourceHandlers) deleteSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req deleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if errs := validateFilter("filter", req.Filter); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, "Validation failed", errs...)
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	filter := normalizeFilter(req.Filter)
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 10*time.Second)
+	defer cancel()
+
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Delete failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":    0,
+		"msg":     "SUCCESS",
+		"deleted": res.DeletedCount,
+	})
+}
+
+// snapshotNaturalKeyFields mirrors the dotted paths a snapshot is naturally
+// keyed by; it backs both the default upsertSnapshot keyFields and the
+// unique index ensured at startup.
+var snapshotNaturalKeyFields = []string{
+	"client_name",
+	"prefix",
+	"data.username",
+	"data.month",
+	"data.year",
+	"data.currency",
+}
+
+type upsertRequest struct {
+	Document  bson.M   `json:"document"`
+	KeyFields []string `json:"keyFields"`
+}
+
+// valueAtPath resolves a dotted field path (e.g. "data.username") against a
+// document decoded from JSON, where nested objects surface as
+// map[string]interface{} rather than bson.M.
+func valueAtPath(doc bson.M, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := asStringMap(cur)
+		if !ok {
+			return nil, false
+		}
+		v, ok := m[part]
+		if !ok {
+			return nil, false
+		}
+		cur = v
+	}
+	return cur, true
+}
+
+func asStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case bson.M:
+		return t, true
+	case map[string]interface{}:
+		return t, true
+	default:
+		return nil, false
+	}
+}
+
+// sameFields reports whether a and b name the same multiset of fields,
+// irrespective of order. Counting occurrences (rather than just checking
+// membership) matters: a keyFields list that repeats one natural-key field
+// in place of another would otherwise pass as "the same fields" while
+// actually building a filter that leaves a real key field unconstrained.
+func sameFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, f := range a {
+		counts[f]++
+	}
+	for _, f := range b {
+		counts[f]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureNaturalKeyIndex creates the unique compound index a resource's
+// documents are upserted against, so concurrent upsertSnapshot calls
+// racing on the same natural key collapse to a single document instead of
+// duplicating rows.
+func ensureNaturalKeyIndex(client *mongo.Client, res resourceConfig) error {
+	keys := bson.D{}
+	for _, f := range res.NaturalKey {
+		keys = append(keys, bson.E{Key: f, Value: 1})
+	}
+
+	collection := client.Database(res.Database).Collection(res.Collection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (h *resourceHandlers) upsertSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req upsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(req.Document) == 0 {
+		writeError(w, http.StatusBadRequest, "Empty document")
+		return
+	}
+	keyFields := req.KeyFields
+	if len(keyFields) == 0 {
+		keyFields = h.res.NaturalKey
+	} else if !sameFields(keyFields, h.res.NaturalKey) {
+		// ensureNaturalKeyIndex only ever builds the unique index on
+		// res.NaturalKey, so a caller-supplied keyFields naming a
+		// different set of fields has no unique index backing it: two
+		// concurrent requests using that key could both pass the
+		// FindOne below and both insert, reopening the exact race this
+		// handler's duplicate-key handling exists to close.
+		writeError(w, http.StatusBadRequest, "Validation failed",
+			ErrorDetail{Field: "keyFields", Rule: "match_natural_key", Message: "keyFields must match the resource's configured natural key"})
+		return
+	}
+
+	filter := bson.M{}
+	for _, field := range keyFields {
+		v, ok := valueAtPath(req.Document, field)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Validation failed",
+				ErrorDetail{Field: field, Rule: "required", Message: fmt.Sprintf("document missing key field %q", field)})
+			return
+		}
+		filter[field] = v
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Database connection error")
+		return
+	}
+
+	collection := client.Database(h.res.Database).Collection(h.res.Collection)
+	ctx, cancel := requestDeadline(r, 10*time.Second)
+	defer cancel()
+
+	var existing bson.M
+	err = collection.FindOne(ctx, filter).Decode(&existing)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(bson.M{
+			"code":    0,
+			"msg":     "SUCCESS",
+			"id":      existing["_id"],
+			"created": false,
+		})
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		writeError(w, http.StatusInternalServerError, "Lookup failed")
+		return
+	}
+
+	res, err := collection.InsertOne(ctx, req.Document)
+	if err != nil {
+		// The FindOne above raced a concurrent upsert on the same natural
+		// key: the unique index rejected our InsertOne, which means the
+		// other caller's document is now in the collection. Re-read it so
+		// the loser of the race gets the same idempotent "already exists"
+		// response as a caller who asked after the winner committed.
+		if mongo.IsDuplicateKeyError(err) {
+			var winner bson.M
+			if lookupErr := collection.FindOne(ctx, filter).Decode(&winner); lookupErr == nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(bson.M{
+					"code":    0,
+					"msg":     "SUCCESS",
+					"id":      winner["_id"],
+					"created": false,
+				})
+				return
+			}
+		}
+		writeError(w, http.StatusInternalServerError, "Insert failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bson.M{
+		"code":    0,
+		"msg":     "SUCCESS",
+		"id":      res.InsertedID,
+		"created": true,
+	})
+}
+
+// streamEvent is one change-stream document fanned out to subscribers,
+// along with its resume token so a reconnecting client can pick up where it
+// left off via the SSE "id" field / Last-Event-ID header.
+type streamEvent struct {
+	id   string
+	data []byte
+}
+
+// sharedStream is one collection.Watch change stream shared by every
+// snapshotStream subscriber that asked for the same filter, so N browsers
+// watching the same query don't open N change streams against Mongo.
+type sharedStream struct {
+	mu          sync.RWMutex
+	subscribers map[chan streamEvent]struct{}
+	cancel      context.CancelFunc
+}
+
+func (s *sharedStream) broadcast(evt streamEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			// Slow subscriber: drop the event rather than block the watch loop.
+		}
+	}
+}
+
+func (s *sharedStream) run(client *mongo.Client, ctx context.Context, database, collectionName string, match bson.M, onDone func()) {
+	defer onDone()
+
+	collection := client.Database(database).Collection(collectionName)
+	var pipeline mongo.Pipeline
+	if len(match) > 0 {
+		pipeline = mongo.Pipeline{{{Key: "$match", Value: match}}}
+	}
+
+	stream, err := collection.Watch(ctx, pipeline)
+	if err != nil {
+		log.Printf("changestream: watch failed: %v", err)
+		return
+	}
+	defer stream.Close(ctx)
+
+	for evt := range pumpChangeStream(ctx, stream) {
+		s.broadcast(evt)
+	}
+}
+
+// pumpChangeStream runs stream.Next in its own goroutine so callers can
+// select between incoming events, a heartbeat ticker, and request
+// cancellation instead of blocking forever on Next.
+func pumpChangeStream(ctx context.Context, stream *mongo.ChangeStream) <-chan streamEvent {
+	out := make(chan streamEvent)
+	go func() {
+		defer close(out)
+		for stream.Next(ctx) {
+			var raw bson.M
+			if err := stream.Decode(&raw); err != nil {
+				continue
+			}
+			data, err := json.Marshal(raw)
+			if err != nil {
+				continue
+			}
+			evt := streamEvent{id: hex.EncodeToString(stream.ResumeToken()), data: data}
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// streamHub keeps one sharedStream per distinct filter key, created lazily
+// on first subscriber and torn down once the last subscriber disconnects.
+type streamHub struct {
+	mu      sync.RWMutex
+	streams map[string]*sharedStream
+}
+
+func (h *streamHub) subscribe(key, database, collectionName string, match bson.M) (*sharedStream, chan streamEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.streams[key]
+	if !ok {
+		client, err := getMongoClient()
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s = &sharedStream{subscribers: make(map[chan streamEvent]struct{}), cancel: cancel}
+		h.streams[key] = s
+		go s.run(client, ctx, database, collectionName, match, func() {
+			h.mu.Lock()
+			if cur, ok := h.streams[key]; ok && cur == s {
+				delete(h.streams, key)
+			}
+			h.mu.Unlock()
+		})
+	}
+
+	ch := make(chan streamEvent, 16)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	return s, ch, nil
+}
+
+func (h *streamHub) unsubscribe(key string, s *sharedStream, ch chan streamEvent) {
+	s.mu.Lock()
+	delete(s.subscribers, ch)
+	empty := len(s.subscribers) == 0
+	s.mu.Unlock()
+
+	if empty {
+		s.cancel()
+	}
+}
+
+func streamKeyFromQuery(q url.Values) string {
+	return "client_name=" + q.Get("client_name") + "&username=" + q.Get("username")
+}
+
+func streamMatchFromQuery(q url.Values) bson.M {
+	match := bson.M{}
+	if v := q.Get("client_name"); v != "" {
+		match["fullDocument.client_name"] = v
+	}
+	if v := q.Get("username"); v != "" {
+		match["fullDocument.data.username"] = v
+	}
+	return match
+}
+
+// serveResumedStream handles a reconnecting client that sent a
+// Last-Event-ID: it opens a dedicated change stream resumed from that
+// token instead of joining the shared fan-out, since the shared stream has
+// no memory of events emitted before the client subscribed.
+func serveResumedStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, database, collectionName string, match bson.M, resumeID string) {
+	token, err := hex.DecodeString(resumeID)
+	if err != nil {
+		return
+	}
+
+	client, err := getMongoClient()
+	if err != nil {
+		return
+	}
+
+	collection := client.Database(database).Collection(collectionName)
+	var pipeline mongo.Pipeline
+	if len(match) > 0 {
+		pipeline = mongo.Pipeline{{{Key: "$match", Value: match}}}
+	}
+
+	stream, err := collection.Watch(ctx, pipeline, options.ChangeStream().SetResumeAfter(bson.Raw(token)))
+	if err != nil {
+		return
+	}
+	defer stream.Close(ctx)
+
+	events := pumpChangeStream(ctx, stream)
+	heartbeat := time.NewTicker(20 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", evt.id, evt.data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *resourceHandlers) snapshotStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	query := r.URL.Query()
+	match := streamMatchFromQuery(query)
+
+	// Streams are meant to run until the client disconnects, so only
+	// bound them if the caller opted into a cutoff via X-Request-Timeout.
+	ctx, cancel := requestDeadlineOptional(r)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if resumeID := r.Header.Get("Last-Event-ID"); resumeID != "" {
+		serveResumedStream(ctx, w, flusher, h.res.Database, h.res.Collection, match, resumeID)
+		return
+	}
+
+	key := streamKeyFromQuery(query)
+	s, ch, err := h.hub.subscribe(key, h.res.Database, h.res.Collection, match)
+	if err != nil {
+		log.Printf("changestream: subscribe failed: %v", err)
+		return
+	}
+	defer h.hub.unsubscribe(key, s, ch)
+
+	heartbeat := time.NewTicker(20 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case evt := <-ch:
+			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", evt.id, evt.data)
+			flusher.Flush()
+		}
+	}
+}
+
+func withCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// withTimeout bounds the whole request to d: once it elapses, r.Context()
+// is cancelled, which propagates into any in-flight Mongo call made via
+// requestDeadline/requestDeadlineOptional below. Compose it inside
+// withCORS (withCORS(withTimeout(d)(handler))) so an OPTIONS preflight
+// returns immediately instead of waiting on the timer.
+func withTimeout(d time.Duration) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// defaultRequestTimeout bounds the generic CRUD/query/bulk routes via
+// withTimeout; it's deliberately longer than any single handler's own
+// Mongo operation timeout so it only kicks in if something gets stuck.
+const defaultRequestTimeout = 45 * time.Second
+
+// requestDeadline is the per-operation equivalent of withTimeout: it
+// derives a Mongo context from r.Context() (so a client disconnect
+// cancels the in-flight Find/Update/etc. immediately instead of letting it
+// run to completion) bounded by def, or by the caller-supplied
+// X-Request-Timeout header when that asks for something tighter.
+func requestDeadline(r *http.Request, def time.Duration) (context.Context, context.CancelFunc) {
+	d := def
+	if v := r.Header.Get("X-Request-Timeout"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 && parsed < d {
+			d = parsed
+		}
+	}
+	return context.WithTimeout(r.Context(), d)
+}
+
+// requestDeadlineOptional is requestDeadline without a default: endpoints
+// like the change-stream subscription are meant to run until the client
+// disconnects, so they stay on r.Context() unmodified unless the caller
+// opts into a cutoff via X-Request-Timeout.
+func requestDeadlineOptional(r *http.Request) (context.Context, context.CancelFunc) {
+	if v := r.Header.Get("X-Request-Timeout"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return context.WithTimeout(r.Context(), d)
+		}
+	}
+	return r.Context(), func() {}
+}
+
+// titleCase upper-cases a name's first rune so it can be spliced into the
+// verbNoun route names registerResourceRoutes builds (e.g. "snapshot" ->
+// "Snapshot" for "insertSnapshot").
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// registerResourceRoutes mounts the generic CRUD+query+bulk+stream handlers
+// for one resource under its configured path prefix, so adding a
+// collection to config.json is enough to expose it - no new route wiring
+// or handler code required. Route names follow the module's original
+// fixed naming convention (nounAll/insertNoun/updateNoun/.../nounStream),
+// parameterized on res.Name, so the default resource's routes still
+// resolve at their original paths (e.g. "/api/v1/ext/querySnapshot")
+// instead of being silently renamed. ReadOnly resources skip every route
+// that can mutate data.
+func registerResourceRoutes(res resourceConfig) {
+	h := newResourceHandlers(res)
+	bounded := withTimeout(defaultRequestTimeout)
+
+	name := res.Name
+	if name == "" {
+		name = res.Collection
+	}
+	title := titleCase(name)
+
+	http.HandleFunc(res.Path+"/"+name+"All", withCORS(bounded(h.snapshotAllHandler)))
+	http.HandleFunc(res.Path+"/query"+title, withCORS(bounded(h.querySnapshotHandler)))
+	http.HandleFunc(res.Path+"/aggregate"+title, withCORS(bounded(h.aggregateSnapshotHandler)))
+	// The stream route is intentionally not wrapped in withTimeout: it's
+	// meant to run until the client disconnects, bounded only if the
+	// caller opts in via X-Request-Timeout (see requestDeadlineOptional).
+	http.HandleFunc(res.Path+"/"+name+"Stream", withCORS(h.snapshotStreamHandler))
+
+	if res.ReadOnly {
+		return
+	}
+	http.HandleFunc(res.Path+"/insert"+title, withCORS(bounded(h.insertSnapshotHandler)))
+	http.HandleFunc(res.Path+"/update"+title, withCORS(bounded(h.updateSnapshotHandler)))
+	http.HandleFunc(res.Path+"/delete"+title, withCORS(bounded(h.deleteSnapshotHandler)))
+	http.HandleFunc(res.Path+"/bulk"+title, withCORS(bounded(h.bulkSnapshotHandler)))
+	http.HandleFunc(res.Path+"/upsert"+title, withCORS(bounded(h.upsertSnapshotHandler)))
+}
+
+func main() {
+	resources := loadResources()
+	for _, res := range resources {
+		registerResourceRoutes(res)
+	}
+
+	// winloseHandler mocks a specific upstream endpoint (ตาม Log จาก
+	// https://api-topup.sportbookprivate.com) rather than a generic
+	// resource route, so it stays on its own fixed path, bound to the
+	// first configured resource's database/collection.
+	if len(resources) > 0 {
+		primary := newResourceHandlers(resources[0])
+		http.HandleFunc("/api/v1/ext/winloseEsByMonthMulti", withCORS(withTimeout(defaultRequestTimeout)(primary.winloseHandler)))
+	}
+
+	if client, err := getMongoClient(); err != nil {
+		log.Printf("mongo: skipping natural-key index setup, no client: %v", err)
+	} else {
+		for _, res := range resources {
+			if res.ReadOnly {
+				continue
+			}
+			if err := ensureNaturalKeyIndex(client, res); err != nil {
+				log.Printf("mongo: failed to ensure natural-key index for %s: %v", res.Path, err)
+			}
+		}
+	}
+	log.Printf("snapshotStream requires the connected MongoDB deployment to be a replica set (change streams are unsupported on standalone servers)")
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	fmt.Printf("Mock Server started at port %s\n", port)
+	fmt.Printf("Endpoint: http://localhost:%s/api/v1/ext/winloseEsByMonthMulti\n", port)
+	for _, res := range resources {
+		fmt.Printf("Resource: http://localhost:%s%s -> %s.%s\n", port, res.Path, res.Database, res.Collection)
+	}
+
+	if err := http.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
+}